@@ -0,0 +1,234 @@
+package cpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRequestTimeout bounds each HTTP request attempt when Provider.HTTPClient is
+// nil, whether or not Provider.RequestTimeout is also set.
+const defaultRequestTimeout = 30 * time.Second
+
+// maxDialRetries bounds the exponential-backoff retries dial performs for 5xx
+// responses, 429s, and network errors.
+const maxDialRetries = 3
+
+// httpError is returned by dialOnce for a 5xx or 429 HTTP response, carrying enough
+// information for dial to decide whether, and how long, to back off.
+type httpError struct {
+	statusCode int
+	retryAfter time.Duration
+	body       string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("cpanel: unexpected HTTP status %d: %s", e.statusCode, e.body)
+}
+
+// httpClient returns the client dial uses to make requests, falling back to a client
+// with defaultRequestTimeout when Provider.HTTPClient is nil.
+func (p *Provider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return &http.Client{Timeout: defaultRequestTimeout}
+}
+
+// dial sends a GET request to the cPanel UAPI, retrying 5xx responses, 429s (honoring
+// Retry-After), and network errors with exponential backoff. Provider.RequestTimeout,
+// if set, bounds the whole call including retries.
+func (p *Provider) dial(ctx context.Context, module, function string, params url.Values) (json.RawMessage, error) {
+	if p.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.RequestTimeout)
+		defer cancel()
+	}
+
+	reqURL := fmt.Sprintf("%s/execute/%s/%s?%s", strings.TrimRight(p.Host, "/"), module, function, params.Encode())
+
+	var lastErr error
+	for attempt := 0; attempt <= maxDialRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, dialBackoff(attempt, lastErr)); err != nil {
+				return nil, err
+			}
+		}
+
+		raw, err := p.dialOnce(ctx, reqURL)
+		if err == nil {
+			return raw, nil
+		}
+		if !isRetryableDialErr(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// dialOnce makes a single attempt at the request dial retries around.
+func (p *Provider) dialOnce(ctx context.Context, reqURL string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(p.Username, p.APIToken)
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &httpError{
+			statusCode: resp.StatusCode,
+			retryAfter: retryAfterDuration(resp.Header.Get("Retry-After")),
+			body:       string(body),
+		}
+	}
+
+	var envelope struct {
+		Result struct {
+			Status   int             `json:"status"`
+			Data     json.RawMessage `json:"data"`
+			Errors   []string        `json:"errors"`
+			Messages []string        `json:"messages"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Result.Status != 1 {
+		return nil, &APIError{Errors: envelope.Result.Errors, Messages: envelope.Result.Messages}
+	}
+	return envelope.Result.Data, nil
+}
+
+// dialStream is dial's counterpart for requests whose response body StreamRecords
+// decodes incrementally instead of buffering: it retries 5xx responses, 429s (honoring
+// Retry-After), and network errors the same way dial does, but returns the open
+// *http.Response for the caller to read rather than decoding it here. The returned
+// cancel func releases the context Provider.RequestTimeout established and must be
+// called once the caller is done reading the body (after dial's retries, the timeout
+// can't simply be deferred away before the body is read).
+func (p *Provider) dialStream(ctx context.Context, reqURL string) (*http.Response, func(), error) {
+	cancel := func() {}
+	if p.RequestTimeout > 0 {
+		var c context.CancelFunc
+		ctx, c = context.WithTimeout(ctx, p.RequestTimeout)
+		cancel = c
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxDialRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, dialBackoff(attempt, lastErr)); err != nil {
+				cancel()
+				return nil, nil, err
+			}
+		}
+
+		resp, err := p.dialStreamOnce(ctx, reqURL)
+		if err == nil {
+			return resp, cancel, nil
+		}
+		if !isRetryableDialErr(err) {
+			cancel()
+			return nil, nil, err
+		}
+		lastErr = err
+	}
+	cancel()
+	return nil, nil, lastErr
+}
+
+// dialStreamOnce makes a single attempt at the request dialStream retries around,
+// returning the response unread (unlike dialOnce, which decodes the whole body itself)
+// once it sees a 2xx status.
+func (p *Provider) dialStreamOnce(ctx context.Context, reqURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(p.Username, p.APIToken)
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &httpError{
+			statusCode: resp.StatusCode,
+			retryAfter: retryAfterDuration(resp.Header.Get("Retry-After")),
+			body:       string(body),
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("cpanel: unexpected HTTP status %d: %s", resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+// isRetryableDialErr reports whether dial should retry after err: a 5xx/429 httpError,
+// or a network-level error from the transport.
+func isRetryableDialErr(err error) bool {
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// dialBackoff computes how long dial waits before the given attempt (1-indexed),
+// honoring a Retry-After carried by the previous attempt's error when present.
+func dialBackoff(attempt int, lastErr error) time.Duration {
+	var httpErr *httpError
+	if errors.As(lastErr, &httpErr) && httpErr.retryAfter > 0 {
+		return httpErr.retryAfter
+	}
+	return time.Duration(math.Pow(2, float64(attempt-1))) * 250 * time.Millisecond
+}
+
+// retryAfterDuration parses an HTTP Retry-After header, which may be either a number
+// of seconds or an HTTP date. It returns 0 if header is empty or unparseable.
+func retryAfterDuration(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// sleepContext waits for d, or returns ctx.Err() if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package cpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDialRetriesTransientServerError(t *testing.T) {
+	var attempts int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"result":{"status":1,"data":true,"errors":[],"messages":[]}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	raw, err := p.dial(context.Background(), "DNS", "has_local_authority", url.Values{"domain": {"example.com"}})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if string(raw) != "true" {
+		t.Errorf("raw = %q, want true", raw)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("attempts = %d, want 2 (one failure, one retry)", got)
+	}
+}
+
+func TestDialHonorsRetryAfterOn429(t *testing.T) {
+	const retryAfter = 1 * time.Second
+	var attempts int32
+	var firstAttempt, secondAttempt time.Time
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			firstAttempt = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		secondAttempt = time.Now()
+		w.Write([]byte(`{"result":{"status":1,"data":true,"errors":[],"messages":[]}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	if _, err := p.dial(context.Background(), "DNS", "has_local_authority", url.Values{"domain": {"example.com"}}); err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if gap := secondAttempt.Sub(firstAttempt); gap < retryAfter {
+		t.Errorf("retried after %s, want at least the %s Retry-After", gap, retryAfter)
+	}
+}
+
+func TestDialGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	_, err := p.dial(context.Background(), "DNS", "has_local_authority", url.Values{"domain": {"example.com"}})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	var httpErr *httpError
+	if !errors.As(err, &httpErr) || httpErr.statusCode != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want *httpError with status 500", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != maxDialRetries+1 {
+		t.Errorf("attempts = %d, want %d (maxDialRetries+1)", got, maxDialRetries+1)
+	}
+}
+
+func TestDialRequestTimeoutBoundsRetries(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token", RequestTimeout: 10 * time.Millisecond}
+
+	start := time.Now()
+	_, err := p.dial(context.Background(), "DNS", "has_local_authority", url.Values{"domain": {"example.com"}})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	// Without RequestTimeout, exhausting maxDialRetries' exponential backoff (250ms,
+	// 500ms, 1s, ...) takes well over a second; a generous fraction of that confirms
+	// the 10ms budget - not the retry loop running to completion - is what cut this short.
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("dial took %s, want it to abort within RequestTimeout's 10ms budget rather than running all %d retries", elapsed, maxDialRetries)
+	}
+}
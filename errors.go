@@ -0,0 +1,37 @@
+package cpanel
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// APIError represents a non-success response from the cPanel UAPI, carrying the
+// envelope's errors and messages arrays so callers can distinguish transient
+// conditions (like a mass_edit_zone serial mismatch) from permanent failures.
+type APIError struct {
+	Errors   []string
+	Messages []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("cpanel: API call failed: %s", strings.Join(e.Errors, "; "))
+	}
+	return "cpanel: API call failed"
+}
+
+// isSerialMismatch reports whether err represents mass_edit_zone rejecting a request
+// because the caller's serial didn't match the zone's current SOA serial.
+func isSerialMismatch(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, e := range apiErr.Errors {
+		if strings.Contains(strings.ToLower(e), "serial") {
+			return true
+		}
+	}
+	return false
+}
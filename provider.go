@@ -2,14 +2,13 @@ package cpanel
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/libdns/libdns"
 )
@@ -19,6 +18,22 @@ type Provider struct {
 	Host     string `json:"host,omitempty"`
 	Username string `json:"username,omitempty"`
 	APIToken string `json:"api_token,omitempty"`
+
+	// MaxSerialRetries bounds how many times a mutating call retries after
+	// mass_edit_zone rejects it for a stale SOA serial. Zero means
+	// defaultMaxSerialRetries.
+	MaxSerialRetries int `json:"-"`
+
+	// HTTPClient is used to make UAPI requests. Nil uses a client with a
+	// defaultRequestTimeout timeout.
+	HTTPClient *http.Client `json:"-"`
+
+	// RequestTimeout bounds a single UAPI call, including dial's retries. Zero means
+	// no additional timeout beyond whatever ctx and HTTPClient already impose.
+	RequestTimeout time.Duration `json:"-"`
+
+	zoneCache   map[string]string
+	zoneCacheMu sync.Mutex
 }
 
 // parseZoneEntry models the result of DNS::parse_zone
@@ -30,156 +45,261 @@ type parseZoneEntry struct {
 	DataB64     []string `json:"data_b64"`
 }
 
-// dial sends a GET request to the cPanel UAPI.
-func (p *Provider) dial(ctx context.Context, module, function string, params url.Values) (json.RawMessage, error) {
-	reqURL := fmt.Sprintf("%s/execute/%s/%s?%s", strings.TrimRight(p.Host, "/"), module, function, params.Encode())
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
-	if err != nil {
+// GetRecords lists all the records in the zone by draining StreamRecords. zone may be
+// either the exact cPanel zone name or any FQDN within it; LookupZone resolves the
+// latter.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	recordsCh, errCh := p.StreamRecords(ctx, zone)
+
+	var records []libdns.Record
+	for r := range recordsCh {
+		records = append(records, r)
+	}
+	if err := <-errCh; err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(p.Username, p.APIToken)
+	return records, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// AppendRecords adds records to the zone using mass_edit_zone, retrying on a serial
+// conflict via withSerialRetry. zone may be either the exact cPanel zone name or any
+// FQDN within it.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	zone, err := p.LookupZone(ctx, zone)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var envelope struct {
-		Result struct {
-			Status int             `json:"status"`
-			Data   json.RawMessage `json:"data"`
-		} `json:"result"`
-	}
+	err = p.withSerialRetry(ctx, zone, func(existing []libdns.Record) error {
+		var add []map[string]interface{}
+		for _, r := range records {
+			data, err := encodeRecordData(r)
+			if err != nil {
+				return err
+			}
+			add = append(add, map[string]interface{}{
+				"record_type": r.Type,
+				"dname":       r.Name + "." + zone,
+				"ttl":         r.TTL,
+				"data":        data,
+			})
+		}
 
-	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		addJson, _ := json.Marshal(add)
+		params := url.Values{
+			"zone":   {zone},
+			"serial": {soaSerial(existing)},
+			"add":    {string(addJson)},
+		}
+		_, err := p.dial(ctx, "DNS", "mass_edit_zone", params)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
-	if envelope.Result.Status != 1 {
-		return nil, errors.New("API call failed")
-	}
-	return envelope.Result.Data, nil
+	return records, nil
 }
 
-// GetRecords lists all the records in the zone.
-func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
-	params := url.Values{"zone": {zone}}
-	raw, err := p.dial(ctx, "DNS", "parse_zone", params)
+// DeleteRecords deletes records from the zone using mass_edit_zone, retrying on a
+// serial conflict via withSerialRetry. zone may be either the exact cPanel zone name
+// or any FQDN within it.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	zone, err := p.LookupZone(ctx, zone)
 	if err != nil {
 		return nil, err
 	}
 
-	var entries []parseZoneEntry
-	if err := json.Unmarshal(raw, &entries); err != nil {
+	err = p.withSerialRetry(ctx, zone, func(existing []libdns.Record) error {
+		var toRemove []string
+		for _, r := range records {
+			for _, ex := range existing {
+				if r.Type == ex.Type && r.Name == ex.Name && r.Value == ex.Value {
+					toRemove = append(toRemove, ex.Metadata["line_index"])
+				}
+			}
+		}
+		removeJson, _ := json.Marshal(toRemove)
+		params := url.Values{
+			"zone":   {zone},
+			"serial": {soaSerial(existing)},
+			"remove": {string(removeJson)},
+		}
+		_, err := p.dial(ctx, "DNS", "mass_edit_zone", params)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	var records []libdns.Record
-	for _, e := range entries {
-		nameBytes, _ := base64.StdEncoding.DecodeString(e.DNameB64)
-		var dataParts []string
-		for _, d := range e.DataB64 {
-			bytes, _ := base64.StdEncoding.DecodeString(d)
-			dataParts = append(dataParts, string(bytes))
-		}
-		records = append(records, libdns.Record{
-			Type:  e.RecordType,
-			Name:  strings.TrimSuffix(string(nameBytes), "."+zone),
-			Value: strings.Join(dataParts, " "),
-			TTL:   uint32(e.TTL),
-			Metadata: map[string]string{
-				"line_index": strconv.Itoa(e.LineIndex),
-			},
-		})
-	}
 	return records, nil
 }
 
-// AppendRecords adds records to the zone using mass_edit_zone.
-func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	// Fetch current serial
-	existing, err := p.GetRecords(ctx, zone)
+// SetRecords replaces records in the zone using mass_edit_zone's "edit" operation,
+// which atomically overwrites a record at a known line_index instead of deleting and
+// re-adding it. That avoids the brief window where delete-then-append makes a record
+// disappear (breaking ACME dns-01 races) and makes one mass_edit_zone call instead of
+// two (plus a follow-up zone fetch when any record is added).
+// A serial conflict is retried via withSerialRetry, which recomputes line_index values
+// against the freshly fetched zone before trying again. zone may be either the exact
+// cPanel zone name or any FQDN within it.
+func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	zone, err := p.LookupZone(ctx, zone)
 	if err != nil {
 		return nil, err
 	}
 
-	serial := "0"
-	for _, r := range existing {
-		if r.Type == "SOA" {
-			parts := strings.Fields(r.Value)
-			if len(parts) >= 3 {
-				serial = parts[2]
+	var result []libdns.Record
+	err = p.withSerialRetry(ctx, zone, func(existing []libdns.Record) error {
+		editLineIndex, removeLineIndex := planSetRecords(existing, records)
+
+		var edit, add []map[string]interface{}
+		result = make([]libdns.Record, len(records))
+		for i, r := range records {
+			data, err := encodeRecordData(r)
+			if err != nil {
+				return err
 			}
-			break
-		}
-	}
 
-	var add []map[string]interface{}
-	for _, r := range records {
-		add = append(add, map[string]interface{}{
-			"record_type": r.Type,
-			"dname":       r.Name + "." + zone,
-			"ttl":         r.TTL,
-			"data":        []string{r.Value},
-		})
-	}
+			if lineIndex := editLineIndex[i]; lineIndex >= 0 {
+				edit = append(edit, map[string]interface{}{
+					"line_index":  lineIndex,
+					"record_type": r.Type,
+					"dname":       r.Name + "." + zone,
+					"ttl":         r.TTL,
+					"data":        data,
+				})
+				r.Metadata = map[string]string{"line_index": strconv.Itoa(lineIndex)}
+			} else {
+				add = append(add, map[string]interface{}{
+					"record_type": r.Type,
+					"dname":       r.Name + "." + zone,
+					"ttl":         r.TTL,
+					"data":        data,
+				})
+			}
+			result[i] = r
+		}
 
-	addJson, _ := json.Marshal(add)
-	params := url.Values{
-		"zone":   {zone},
-		"serial": {serial},
-		"add":    {string(addJson)},
-	}
+		params := url.Values{"zone": {zone}, "serial": {soaSerial(existing)}}
+		if edit != nil {
+			editJson, _ := json.Marshal(edit)
+			params.Set("edit", string(editJson))
+		}
+		if add != nil {
+			addJson, _ := json.Marshal(add)
+			params.Set("add", string(addJson))
+		}
+		if removeLineIndex != nil {
+			removeJson, _ := json.Marshal(removeLineIndex)
+			params.Set("remove", string(removeJson))
+		}
 
-	_, err = p.dial(ctx, "DNS", "mass_edit_zone", params)
+		if _, err := p.dial(ctx, "DNS", "mass_edit_zone", params); err != nil {
+			return err
+		}
+		if add == nil {
+			return nil
+		}
+		return p.populateAddedLineIndex(ctx, zone, result, editLineIndex)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return records, nil
+	return result, nil
 }
 
-// DeleteRecords deletes records from the zone using mass_edit_zone.
-func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	existing, err := p.GetRecords(ctx, zone)
+// populateAddedLineIndex fills in Metadata["line_index"] for the records SetRecords
+// just created via mass_edit_zone's "add" operation, which - unlike "edit" - doesn't
+// echo back the line_index it assigned each new record. It re-fetches the zone and
+// matches each added record by (type, name, value), one fresh record per added record
+// (claimLineIndex claims existing records for edit the same one-at-a-time way, though
+// by (type, name) only), so added records come back with the same line_index metadata
+// edited ones already carry.
+func (p *Provider) populateAddedLineIndex(ctx context.Context, zone string, result []libdns.Record, editLineIndex []int) error {
+	fresh, err := p.GetRecords(ctx, zone)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	serial := "0"
-	var toRemove []string
-	for _, r := range records {
-		for _, ex := range existing {
-			if r.Type == ex.Type && r.Name == ex.Name && r.Value == ex.Value {
-				toRemove = append(toRemove, ex.Metadata["line_index"])
-				if ex.Type == "SOA" {
-					parts := strings.Fields(ex.Value)
-					if len(parts) >= 3 {
-						serial = parts[2]
-					}
-				}
+	for i, lineIndex := range editLineIndex {
+		if lineIndex >= 0 {
+			continue
+		}
+		for j, f := range fresh {
+			if f.Type != result[i].Type || f.Name != result[i].Name || f.Value != result[i].Value {
+				continue
 			}
+			result[i].Metadata = map[string]string{"line_index": f.Metadata["line_index"]}
+			fresh = append(fresh[:j:j], fresh[j+1:]...)
+			break
 		}
 	}
-	removeJson, _ := json.Marshal(toRemove)
-	params := url.Values{
-		"zone":   {zone},
-		"serial": {serial},
-		"remove": {string(removeJson)},
+	return nil
+}
+
+// planSetRecords decides how SetRecords should reconcile existing against the desired
+// records: which incoming record (by index) overwrites which existing line_index, and
+// which existing lines - belonging to a (type, name) pair the incoming records
+// touch, but not claimed by any of them - must be removed so the touched RRsets end
+// up containing exactly the records provided. It matches existing records one at a
+// time (each existing record can back at most one incoming record), so a multi-value
+// RRset like round-robin A records doesn't collapse onto a single line_index.
+func planSetRecords(existing, records []libdns.Record) (editLineIndex []int, removeLineIndex []string) {
+	available := make([]libdns.Record, len(existing))
+	copy(available, existing)
+
+	editLineIndex = make([]int, len(records))
+	for i, r := range records {
+		if lineIndex, ok := claimLineIndex(&available, r); ok {
+			editLineIndex[i] = lineIndex
+		} else {
+			editLineIndex[i] = -1
+		}
 	}
-	_, err = p.dial(ctx, "DNS", "mass_edit_zone", params)
-	if err != nil {
-		return nil, err
+
+	touched := make(map[[2]string]bool, len(records))
+	for _, r := range records {
+		touched[[2]string{r.Type, r.Name}] = true
 	}
-	return records, nil
+	for _, ex := range available {
+		if touched[[2]string{ex.Type, ex.Name}] {
+			removeLineIndex = append(removeLineIndex, ex.Metadata["line_index"])
+		}
+	}
+
+	return editLineIndex, removeLineIndex
 }
 
-// SetRecords replaces records by deleting and then appending them.
-func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	_, err := p.DeleteRecords(ctx, zone, records)
-	if err != nil {
-		return nil, err
+// claimLineIndex finds the first record in *available matching r by (type, name),
+// removes it from *available so a later call in the same SetRecords pass can't
+// claim it again, and returns its line_index.
+func claimLineIndex(available *[]libdns.Record, r libdns.Record) (int, bool) {
+	for i, ex := range *available {
+		if ex.Type != r.Type || ex.Name != r.Name {
+			continue
+		}
+		lineIndex, err := strconv.Atoi(ex.Metadata["line_index"])
+		if err != nil {
+			continue
+		}
+		*available = append((*available)[:i:i], (*available)[i+1:]...)
+		return lineIndex, true
+	}
+	return 0, false
+}
+
+// soaSerial extracts a zone's current serial from its SOA record, defaulting to "0" if
+// one isn't present (mass_edit_zone then performs no serial check).
+func soaSerial(records []libdns.Record) string {
+	for _, r := range records {
+		if r.Type == "SOA" {
+			parts := strings.Fields(r.Value)
+			if len(parts) >= 3 {
+				return parts[2]
+			}
+			break
+		}
 	}
-	return p.AppendRecords(ctx, zone, records)
+	return "0"
 }
 
 var (
@@ -0,0 +1,177 @@
+package cpanel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func rec(rtype, name, value, lineIndex string) libdns.Record {
+	return libdns.Record{
+		Type:     rtype,
+		Name:     name,
+		Value:    value,
+		Metadata: map[string]string{"line_index": lineIndex},
+	}
+}
+
+func TestPlanSetRecordsMultiValueRRset(t *testing.T) {
+	existing := []libdns.Record{
+		rec("A", "www", "192.0.2.1", "5"),
+		rec("A", "www", "192.0.2.2", "6"),
+	}
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.10"},
+		{Type: "A", Name: "www", Value: "192.0.2.11"},
+	}
+
+	editLineIndex, removeLineIndex := planSetRecords(existing, records)
+
+	if !reflect.DeepEqual(editLineIndex, []int{5, 6}) {
+		t.Errorf("editLineIndex = %v, want [5 6]", editLineIndex)
+	}
+	if len(removeLineIndex) != 0 {
+		t.Errorf("removeLineIndex = %v, want none", removeLineIndex)
+	}
+}
+
+func TestPlanSetRecordsRemovesUnclaimedExisting(t *testing.T) {
+	existing := []libdns.Record{
+		rec("A", "www", "192.0.2.1", "5"),
+		rec("A", "www", "192.0.2.2", "6"),
+	}
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.10"},
+	}
+
+	editLineIndex, removeLineIndex := planSetRecords(existing, records)
+
+	if !reflect.DeepEqual(editLineIndex, []int{5}) {
+		t.Errorf("editLineIndex = %v, want [5]", editLineIndex)
+	}
+	if !reflect.DeepEqual(removeLineIndex, []string{"6"}) {
+		t.Errorf("removeLineIndex = %v, want [6]", removeLineIndex)
+	}
+}
+
+func TestPlanSetRecordsLeavesUntouchedRRsetsAlone(t *testing.T) {
+	existing := []libdns.Record{
+		rec("A", "www", "192.0.2.1", "5"),
+		rec("TXT", "www", "unrelated", "6"),
+	}
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.10"},
+	}
+
+	editLineIndex, removeLineIndex := planSetRecords(existing, records)
+
+	if !reflect.DeepEqual(editLineIndex, []int{5}) {
+		t.Errorf("editLineIndex = %v, want [5]", editLineIndex)
+	}
+	if len(removeLineIndex) != 0 {
+		t.Errorf("removeLineIndex = %v, want none", removeLineIndex)
+	}
+}
+
+// TestSetRecordsEditsAddsAndPopulatesLineIndex drives SetRecords against a fake UAPI
+// server to verify it builds mass_edit_zone's edit/add operations atomically against
+// the zone's current serial, and - since mass_edit_zone's "add" operation doesn't echo
+// back the line_index it assigns - follows up with a fetch to populate Metadata for the
+// records it added, the same as it already does inline for the ones it edited.
+func TestSetRecordsEditsAddsAndPopulatesLineIndex(t *testing.T) {
+	soaFields := []string{
+		encodeB64("ns1.example.com"), encodeB64("hostmaster.example.com"), encodeB64("2024010100"),
+		encodeB64("86400"), encodeB64("7200"), encodeB64("3600000"), encodeB64("300"),
+	}
+	initial := fmt.Sprintf(`{"result":{"status":1,"data":[`+
+		`{"line_index":1,"record_type":"SOA","ttl":86400,"dname_b64":%q,"data_b64":[%q,%q,%q,%q,%q,%q,%q]},`+
+		`{"line_index":5,"record_type":"A","ttl":300,"dname_b64":%q,"data_b64":[%q]},`+
+		`{"line_index":6,"record_type":"TXT","ttl":300,"dname_b64":%q,"data_b64":[%q]}`+
+		`],"errors":[],"messages":[]}}`,
+		encodeB64("example.com"), soaFields[0], soaFields[1], soaFields[2], soaFields[3], soaFields[4], soaFields[5], soaFields[6],
+		encodeB64("www.example.com"), encodeB64("192.0.2.1"),
+		encodeB64("foo.example.com"), encodeB64("unrelated"))
+
+	updated := fmt.Sprintf(`{"result":{"status":1,"data":[`+
+		`{"line_index":1,"record_type":"SOA","ttl":86400,"dname_b64":%q,"data_b64":[%q,%q,%q,%q,%q,%q,%q]},`+
+		`{"line_index":5,"record_type":"A","ttl":300,"dname_b64":%q,"data_b64":[%q]},`+
+		`{"line_index":6,"record_type":"TXT","ttl":300,"dname_b64":%q,"data_b64":[%q]},`+
+		`{"line_index":7,"record_type":"A","ttl":300,"dname_b64":%q,"data_b64":[%q]}`+
+		`],"errors":[],"messages":[]}}`,
+		encodeB64("example.com"), soaFields[0], soaFields[1], soaFields[2], soaFields[3], soaFields[4], soaFields[5], soaFields[6],
+		encodeB64("www.example.com"), encodeB64("192.0.2.99"),
+		encodeB64("foo.example.com"), encodeB64("unrelated"),
+		encodeB64("www2.example.com"), encodeB64("192.0.2.50"))
+
+	var parseZoneCalls int32
+	var massEditParams url.Values
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"status":1,"data":true,"errors":[],"messages":[]}}`)
+	})
+	mux.HandleFunc("/execute/DNS/parse_zone", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&parseZoneCalls, 1) == 1 {
+			fmt.Fprint(w, initial)
+			return
+		}
+		fmt.Fprint(w, updated)
+	})
+	mux.HandleFunc("/execute/DNS/mass_edit_zone", func(w http.ResponseWriter, r *http.Request) {
+		massEditParams = r.URL.Query()
+		fmt.Fprint(w, `{"result":{"status":1,"data":null,"errors":[],"messages":[]}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.99", TTL: 300},
+		{Type: "A", Name: "www2", Value: "192.0.2.50", TTL: 300},
+	}
+
+	result, err := p.SetRecords(context.Background(), "example.com", records)
+	if err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+
+	if massEditParams.Get("serial") != "2024010100" {
+		t.Errorf("serial = %q, want 2024010100", massEditParams.Get("serial"))
+	}
+
+	var edit []map[string]interface{}
+	if err := json.Unmarshal([]byte(massEditParams.Get("edit")), &edit); err != nil {
+		t.Fatalf("edit json: %v", err)
+	}
+	if len(edit) != 1 || edit[0]["line_index"].(float64) != 5 {
+		t.Errorf("edit = %v, want one entry at line_index 5", edit)
+	}
+
+	var add []map[string]interface{}
+	if err := json.Unmarshal([]byte(massEditParams.Get("add")), &add); err != nil {
+		t.Fatalf("add json: %v", err)
+	}
+	if len(add) != 1 || add[0]["dname"] != "www2.example.com" {
+		t.Errorf("add = %v, want one entry for www2.example.com", add)
+	}
+
+	if result[0].Metadata["line_index"] != "5" {
+		t.Errorf("result[0] line_index = %q, want 5", result[0].Metadata["line_index"])
+	}
+	if result[1].Metadata["line_index"] != "7" {
+		t.Errorf("result[1] line_index = %q, want 7 (populated via follow-up fetch)", result[1].Metadata["line_index"])
+	}
+
+	if got := atomic.LoadInt32(&parseZoneCalls); got != 2 {
+		t.Errorf("parse_zone called %d times, want 2 (initial fetch, then the follow-up after add)", got)
+	}
+}
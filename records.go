@@ -0,0 +1,210 @@
+package cpanel
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// decodeParseZoneEntry turns one parse_zone entry into a libdns.Record. StreamRecords
+// uses it for every entry, and GetRecords sees the same decoding by draining
+// StreamRecords.
+func decodeParseZoneEntry(e parseZoneEntry, zone string) (libdns.Record, error) {
+	nameBytes, _ := base64.StdEncoding.DecodeString(e.DNameB64)
+
+	var dataParts []string
+	for _, d := range e.DataB64 {
+		decoded, _ := base64.StdEncoding.DecodeString(d)
+		dataParts = append(dataParts, string(decoded))
+	}
+
+	value, priority, metadata, err := decodeRecordData(e.RecordType, dataParts)
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	recordMetadata := map[string]string{"line_index": strconv.Itoa(e.LineIndex)}
+	for k, v := range metadata {
+		recordMetadata[k] = v
+	}
+
+	return libdns.Record{
+		Type:     e.RecordType,
+		Name:     strings.TrimSuffix(string(nameBytes), "."+zone),
+		Value:    value,
+		Priority: priority,
+		TTL:      uint32(e.TTL),
+		Metadata: recordMetadata,
+	}, nil
+}
+
+// recordCodec describes how a record type's RDATA fields translate to and from a
+// libdns.Record. Types absent from recordCodecs fall back to joining fields with spaces
+// on decode and sending Value as a single field on encode, which is correct for
+// single-field types like A, AAAA, CNAME, NS, and PTR.
+type recordCodec struct {
+	// decode turns the positional fields parse_zone returns (after base64-decoding)
+	// into a Value, for MX/SRV a Priority, and - for types like TXT whose fields don't
+	// fully determine how to re-split Value later - any extra Metadata encode needs.
+	decode func(fields []string) (value string, priority uint32, metadata map[string]string, err error)
+	// encode turns a libdns.Record back into the positional fields mass_edit_zone's
+	// "data" array expects, one element per RDATA field.
+	encode func(r libdns.Record) ([]string, error)
+}
+
+// recordCodecs is the table of record types whose RDATA layout doesn't survive a naive
+// space-join: multi-field types (MX, SRV, CAA, SOA) and multi-chunk TXT.
+var recordCodecs = map[string]recordCodec{
+	"MX":  {decodeMX, encodeMX},
+	"SRV": {decodeSRV, encodeSRV},
+	"CAA": {decodeCAA, encodeCAA},
+	"TXT": {decodeTXT, encodeTXT},
+	"SOA": {decodeSOA, encodeSOA},
+}
+
+// decodeRecordData turns a parse_zone entry's RDATA fields into a Value, for MX/SRV a
+// Priority, and any extra Metadata encodeRecordData needs to reverse the decoding.
+// Unlisted types are space-joined, matching prior behavior.
+func decodeRecordData(rtype string, fields []string) (value string, priority uint32, metadata map[string]string, err error) {
+	if codec, ok := recordCodecs[rtype]; ok {
+		return codec.decode(fields)
+	}
+	return strings.Join(fields, " "), 0, nil, nil
+}
+
+// encodeRecordData turns a libdns.Record into the "data" array mass_edit_zone expects.
+// Unlisted types are sent as a single field, matching prior behavior.
+func encodeRecordData(r libdns.Record) ([]string, error) {
+	if codec, ok := recordCodecs[r.Type]; ok {
+		return codec.encode(r)
+	}
+	return []string{r.Value}, nil
+}
+
+func decodeMX(fields []string) (string, uint32, map[string]string, error) {
+	if len(fields) != 2 {
+		return "", 0, nil, fmt.Errorf("cpanel: MX record has %d fields, want 2 (preference, exchange)", len(fields))
+	}
+	preference, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("cpanel: MX preference %q: %w", fields[0], err)
+	}
+	return fields[1], uint32(preference), nil, nil
+}
+
+func encodeMX(r libdns.Record) ([]string, error) {
+	return []string{strconv.FormatUint(uint64(r.Priority), 10), r.Value}, nil
+}
+
+func decodeSRV(fields []string) (string, uint32, map[string]string, error) {
+	if len(fields) != 4 {
+		return "", 0, nil, fmt.Errorf("cpanel: SRV record has %d fields, want 4 (priority, weight, port, target)", len(fields))
+	}
+	priority, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("cpanel: SRV priority %q: %w", fields[0], err)
+	}
+	return strings.Join(fields[1:], " "), uint32(priority), nil, nil
+}
+
+func encodeSRV(r libdns.Record) ([]string, error) {
+	parts := strings.Fields(r.Value)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("cpanel: SRV value %q must be \"weight port target\"", r.Value)
+	}
+	return append([]string{strconv.FormatUint(uint64(r.Priority), 10)}, parts...), nil
+}
+
+func decodeCAA(fields []string) (string, uint32, map[string]string, error) {
+	if len(fields) != 3 {
+		return "", 0, nil, fmt.Errorf("cpanel: CAA record has %d fields, want 3 (flags, tag, value)", len(fields))
+	}
+	return fmt.Sprintf("%s %s %q", fields[0], fields[1], fields[2]), 0, nil, nil
+}
+
+func encodeCAA(r libdns.Record) ([]string, error) {
+	parts := strings.SplitN(r.Value, " ", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("cpanel: CAA value %q must be \"flags tag value\"", r.Value)
+	}
+	if unquoted, err := strconv.Unquote(parts[2]); err == nil {
+		parts[2] = unquoted
+	}
+	return parts, nil
+}
+
+// decodeTXT passes a single-chunk TXT value through unquoted, matching the plain
+// strings the overwhelmingly common case (SPF, DKIM, ACME dns-01 challenges, ...)
+// expects. A value parse_zone actually split into multiple chunks is instead quoted and
+// joined in the multi-string presentation zone files use for TXT records (e.g.
+// `"v=spf1 include:example.com " "~all"`), which preserves the original chunk
+// boundaries losslessly; the chunk count is recorded in Metadata["txt_chunks"] so
+// encodeTXT knows to split it back apart without having to guess from Value's shape,
+// which is ambiguous for a single-chunk value that itself looks quoted.
+func decodeTXT(fields []string) (string, uint32, map[string]string, error) {
+	if len(fields) == 1 {
+		return fields[0], 0, nil, nil
+	}
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = strconv.Quote(f)
+	}
+	metadata := map[string]string{"txt_chunks": strconv.Itoa(len(fields))}
+	return strings.Join(quoted, " "), 0, metadata, nil
+}
+
+// encodeTXT is the inverse of decodeTXT: Metadata["txt_chunks"] says how many chunks
+// parse_zone originally split Value into. Anything less than 2 - including a record
+// built directly by a caller, which carries no txt_chunks metadata - is sent through as
+// a single chunk; only a value decodeTXT actually quoted is split back into its
+// original chunks.
+func encodeTXT(r libdns.Record) ([]string, error) {
+	chunks, _ := strconv.Atoi(r.Metadata["txt_chunks"])
+	if chunks < 2 {
+		return []string{r.Value}, nil
+	}
+
+	var fields []string
+	rest := r.Value
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if rest == "" {
+			break
+		}
+		prefix, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("cpanel: TXT value %q must be space-separated quoted strings: %w", r.Value, err)
+		}
+		unquoted, err := strconv.Unquote(prefix)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, unquoted)
+		rest = rest[len(prefix):]
+	}
+	if len(fields) != chunks {
+		return nil, fmt.Errorf("cpanel: TXT value %q has %d chunks, want %d per txt_chunks metadata", r.Value, len(fields), chunks)
+	}
+	return fields, nil
+}
+
+// decodeSOA joins the seven SOA fields in their canonical order: mname, rname, serial,
+// refresh, retry, expire, minimum.
+func decodeSOA(fields []string) (string, uint32, map[string]string, error) {
+	if len(fields) != 7 {
+		return "", 0, nil, fmt.Errorf("cpanel: SOA record has %d fields, want 7", len(fields))
+	}
+	return strings.Join(fields, " "), 0, nil, nil
+}
+
+// encodeSOA splits a SOA value back into its seven canonical fields.
+func encodeSOA(r libdns.Record) ([]string, error) {
+	fields := strings.Fields(r.Value)
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("cpanel: SOA value %q must have 7 space-separated fields, got %d", r.Value, len(fields))
+	}
+	return fields, nil
+}
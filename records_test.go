@@ -0,0 +1,77 @@
+package cpanel
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRecordCodecRoundTrip(t *testing.T) {
+	cases := []struct {
+		rtype  string
+		fields []string
+	}{
+		{"MX", []string{"10", "mail.example.com"}},
+		{"SRV", []string{"10", "20", "5269", "target.example.com"}},
+		{"CAA", []string{"0", "issue", "letsencrypt.org"}},
+		{"TXT", []string{"v=spf1 include:example.com ~all"}},
+		{"TXT", []string{"v=spf1 include:example.com ", "~all"}},
+		{"SOA", []string{"ns1.example.com", "hostmaster.example.com", "2024010100", "86400", "7200", "3600000", "300"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.rtype, func(t *testing.T) {
+			value, priority, metadata, err := decodeRecordData(c.rtype, c.fields)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			data, err := encodeRecordData(libdns.Record{Type: c.rtype, Value: value, Priority: priority, Metadata: metadata})
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			if len(data) != len(c.fields) {
+				t.Fatalf("encode produced %d fields, want %d: %v", len(data), len(c.fields), data)
+			}
+			for i := range c.fields {
+				if data[i] != c.fields[i] {
+					t.Errorf("field %d = %q, want %q", i, data[i], c.fields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeRecordDataFallback(t *testing.T) {
+	value, priority, metadata, err := decodeRecordData("A", []string{"192.0.2.1"})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if value != "192.0.2.1" || priority != 0 || metadata != nil {
+		t.Errorf("got (%q, %d, %v), want (\"192.0.2.1\", 0, nil)", value, priority, metadata)
+	}
+}
+
+// TestEncodeTXTIgnoresQuoteLikeSingleChunkValue guards against encodeTXT misreading a
+// single-chunk TXT value that happens to look quoted (e.g. a record whose content is
+// itself the literal text `"exact"`) as decodeTXT's multi-chunk presentation: without
+// Metadata["txt_chunks"] to say otherwise, it must round-trip as one chunk, quotes and
+// all, rather than silently stripping them.
+func TestEncodeTXTIgnoresQuoteLikeSingleChunkValue(t *testing.T) {
+	value, _, metadata, err := decodeTXT([]string{`"exact"`})
+	if err != nil {
+		t.Fatalf("decodeTXT: %v", err)
+	}
+	if value != `"exact"` {
+		t.Fatalf("decodeTXT value = %q, want %q", value, `"exact"`)
+	}
+
+	data, err := encodeTXT(libdns.Record{Type: "TXT", Value: value, Metadata: metadata})
+	if err != nil {
+		t.Fatalf("encodeTXT: %v", err)
+	}
+	if len(data) != 1 || data[0] != `"exact"` {
+		t.Errorf("encodeTXT = %v, want [%q]", data, `"exact"`)
+	}
+}
@@ -0,0 +1,43 @@
+package cpanel
+
+import (
+	"context"
+
+	"github.com/libdns/libdns"
+)
+
+// defaultMaxSerialRetries is used when Provider.MaxSerialRetries is zero.
+const defaultMaxSerialRetries = 5
+
+// withSerialRetry fetches the zone's current records and invokes fn with them, retrying
+// whenever cPanel rejects fn's mass_edit_zone call for a stale serial. Before each
+// retry it re-fetches the zone so the serial and any line_index values fn computes from
+// existing reflect the latest state. It gives up after Provider.MaxSerialRetries
+// retries (default defaultMaxSerialRetries), i.e. one more attempt than that in all,
+// and returns the last error.
+func (p *Provider) withSerialRetry(ctx context.Context, zone string, fn func(existing []libdns.Record) error) error {
+	existing, err := p.GetRecords(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	maxRetries := p.MaxSerialRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxSerialRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn(existing)
+		if err == nil {
+			return nil
+		}
+		if !isSerialMismatch(err) || attempt >= maxRetries {
+			return err
+		}
+
+		existing, err = p.GetRecords(ctx, zone)
+		if err != nil {
+			return err
+		}
+	}
+}
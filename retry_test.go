@@ -0,0 +1,72 @@
+package cpanel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+// TestWithSerialRetryRefetchesOnSerialMismatch drives AppendRecords (the simplest
+// withSerialRetry caller) against a fake UAPI server that rejects the first
+// mass_edit_zone call for a stale serial, verifying withSerialRetry re-fetches the
+// zone's current serial and succeeds on the next attempt rather than giving up or
+// retrying with the same stale value.
+func TestWithSerialRetryRefetchesOnSerialMismatch(t *testing.T) {
+	soaEnvelope := func(serial string) string {
+		return fmt.Sprintf(`{"result":{"status":1,"data":[`+
+			`{"line_index":1,"record_type":"SOA","ttl":86400,"dname_b64":%q,"data_b64":[%q,%q,%q,%q,%q,%q,%q]}`+
+			`],"errors":[],"messages":[]}}`,
+			encodeB64("example.com"), encodeB64("ns1.example.com"), encodeB64("hostmaster.example.com"),
+			encodeB64(serial), encodeB64("86400"), encodeB64("7200"), encodeB64("3600000"), encodeB64("300"))
+	}
+
+	var parseZoneCalls, massEditCalls int32
+	var serials []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"status":1,"data":true,"errors":[],"messages":[]}}`)
+	})
+	mux.HandleFunc("/execute/DNS/parse_zone", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&parseZoneCalls, 1) == 1 {
+			fmt.Fprint(w, soaEnvelope("1"))
+			return
+		}
+		fmt.Fprint(w, soaEnvelope("2"))
+	})
+	mux.HandleFunc("/execute/DNS/mass_edit_zone", func(w http.ResponseWriter, r *http.Request) {
+		serials = append(serials, r.URL.Query().Get("serial"))
+		if atomic.AddInt32(&massEditCalls, 1) == 1 {
+			fmt.Fprint(w, `{"result":{"status":0,"data":null,"errors":["mass_edit_zone: serial mismatch"],"messages":[]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"result":{"status":1,"data":null,"errors":[],"messages":[]}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	records := []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 300}}
+	if _, err := p.AppendRecords(context.Background(), "example.com", records); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&massEditCalls); got != 2 {
+		t.Fatalf("mass_edit_zone called %d times, want 2 (one rejection, one success)", got)
+	}
+	if got := atomic.LoadInt32(&parseZoneCalls); got != 2 {
+		t.Fatalf("parse_zone called %d times, want 2 (initial fetch, then re-fetch after the mismatch)", got)
+	}
+	wantSerials := []string{"1", "2"}
+	for i, want := range wantSerials {
+		if serials[i] != want {
+			t.Errorf("attempt %d used serial %q, want %q", i, serials[i], want)
+		}
+	}
+}
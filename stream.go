@@ -0,0 +1,229 @@
+package cpanel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// streamChunkSize bounds how many parse_zone entries a single request asks cPanel
+// for. StreamRecords pages through api.chunk.start/api.chunk.size in increments of
+// this size, the same UAPI pagination mechanism ListZones uses in zones.go, rather
+// than a one-shot streamed transfer.
+const streamChunkSize = 500
+
+// StreamRecords lists the records in zone one at a time over the returned channel,
+// paging through UAPI's api.chunk.start/api.chunk.size mechanism (streamChunkSize
+// entries per page, mirroring ListZones' pagination in zones.go) and decoding each
+// page's result.data array incrementally with json.Decoder, so large zones - common
+// on shared/reseller cPanel hosts - never have to sit fully in memory at once.
+//
+// The record channel is always closed when streaming ends. The error channel receives
+// at most one error (nil on success) and is also closed; callers should range over the
+// record channel and then receive from the error channel, in that order, as GetRecords
+// does. zone may be either the exact cPanel zone name or any FQDN within it.
+func (p *Provider) StreamRecords(ctx context.Context, zone string) (<-chan libdns.Record, <-chan error) {
+	records := make(chan libdns.Record)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(records)
+		defer close(errs)
+
+		resolvedZone, err := p.LookupZone(ctx, zone)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		for offset := 0; ; offset += streamChunkSize {
+			count, err := p.streamPage(ctx, resolvedZone, offset, records)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if count < streamChunkSize {
+				return
+			}
+		}
+	}()
+
+	return records, errs
+}
+
+// streamPage fetches one page of up to streamChunkSize records starting at offset and
+// streams them onto records, returning how many it sent so StreamRecords knows
+// whether a further page is needed.
+func (p *Provider) streamPage(ctx context.Context, zone string, offset int, records chan<- libdns.Record) (int, error) {
+	resp, cancel, err := p.streamParseZone(ctx, zone, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	return decodeStreamPage(json.NewDecoder(resp.Body), zone, ctx, records)
+}
+
+// streamParseZone issues one page of the chunked parse_zone request StreamRecords
+// decodes incrementally, via dialStream so a transient 5xx/429/network failure on one
+// page retries with the same backoff and Retry-After handling dial gives every other
+// UAPI call, rather than aborting the whole listing.
+func (p *Provider) streamParseZone(ctx context.Context, zone string, offset int) (*http.Response, func(), error) {
+	params := url.Values{
+		"zone":            {zone},
+		"api.chunk.start": {strconv.Itoa(offset)},
+		"api.chunk.size":  {strconv.Itoa(streamChunkSize)},
+	}
+	reqURL := fmt.Sprintf("%s/execute/DNS/parse_zone?%s", strings.TrimRight(p.Host, "/"), params.Encode())
+
+	return p.dialStream(ctx, reqURL)
+}
+
+// decodeStreamPage walks dec's tokens across one UAPI envelope, streaming each
+// element of result.data onto records as soon as it's decoded rather than buffering
+// the whole array, while also capturing result.status/errors/messages - wherever they
+// fall relative to data - so a failed call surfaces the same *APIError dial returns
+// instead of silently yielding zero records. It returns how many records it sent.
+func decodeStreamPage(dec *json.Decoder, zone string, ctx context.Context, records chan<- libdns.Record) (int, error) {
+	if _, err := dec.Token(); err != nil { // opening '{' of the envelope
+		return 0, err
+	}
+	if err := seekKey(dec, "result"); err != nil {
+		return 0, err
+	}
+	if _, err := dec.Token(); err != nil { // opening '{' of "result"
+		return 0, err
+	}
+
+	var status int
+	haveStatus := false
+	var apiErrors, messages []string
+	count := 0
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, err
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "status":
+			if err := dec.Decode(&status); err != nil {
+				return 0, err
+			}
+			haveStatus = true
+		case "errors":
+			if err := dec.Decode(&apiErrors); err != nil {
+				return 0, err
+			}
+		case "messages":
+			if err := dec.Decode(&messages); err != nil {
+				return 0, err
+			}
+		case "data":
+			n, err := streamDataArray(dec, zone, ctx, records)
+			if err != nil {
+				return 0, err
+			}
+			count = n
+		default:
+			if err := skipValue(dec); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if haveStatus && status != 1 {
+		return 0, &APIError{Errors: apiErrors, Messages: messages}
+	}
+	return count, nil
+}
+
+// streamDataArray decodes dec's current "data" array one element at a time, sending
+// each as a libdns.Record on records, and returns how many it sent.
+func streamDataArray(dec *json.Decoder, zone string, ctx context.Context, records chan<- libdns.Record) (int, error) {
+	if _, err := dec.Token(); err != nil { // opening '['
+		return 0, err
+	}
+
+	count := 0
+	for dec.More() {
+		var e parseZoneEntry
+		if err := dec.Decode(&e); err != nil {
+			return 0, err
+		}
+
+		record, err := decodeParseZoneEntry(e, zone)
+		if err != nil {
+			return 0, err
+		}
+
+		select {
+		case records <- record:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		count++
+	}
+
+	if _, err := dec.Token(); err != nil { // closing ']'
+		return 0, err
+	}
+	return count, nil
+}
+
+// seekKey advances dec past the current object's keys until it finds key, leaving dec
+// positioned to decode that key's value next. Other keys' values are skipped whole.
+func seekKey(dec *json.Decoder, key string) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if name, ok := tok.(string); ok && name == key {
+			return nil
+		}
+		if err := skipValue(dec); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("cpanel: UAPI response missing %q", key)
+}
+
+// skipValue consumes and discards the next complete JSON value (scalar, object, or
+// array), leaving dec positioned after it.
+func skipValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+
+	depth := 1
+	for depth > 0 {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+	return nil
+}
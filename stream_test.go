@@ -0,0 +1,96 @@
+package cpanel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func encodeB64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestDecodeStreamPageDecodesRecords(t *testing.T) {
+	envelope := fmt.Sprintf(`{"result":{"status":1,"data":[{"line_index":1,"record_type":"A","ttl":300,"dname_b64":%q,"data_b64":[%q]}],"errors":[],"messages":[]}}`,
+		encodeB64("www.example.com"), encodeB64("192.0.2.1"))
+
+	records := make(chan libdns.Record, 1)
+	count, err := decodeStreamPage(json.NewDecoder(strings.NewReader(envelope)), "example.com", context.Background(), records)
+	if err != nil {
+		t.Fatalf("decodeStreamPage: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+
+	r := <-records
+	if r.Type != "A" || r.Name != "www" || r.Value != "192.0.2.1" {
+		t.Errorf("got %+v, want A www 192.0.2.1", r)
+	}
+}
+
+func TestDecodeStreamPageSurfacesAPIError(t *testing.T) {
+	envelope := `{"result":{"status":0,"data":[],"errors":["mass_edit_zone: serial mismatch"],"messages":[]}}`
+
+	records := make(chan libdns.Record, 1)
+	_, err := decodeStreamPage(json.NewDecoder(strings.NewReader(envelope)), "example.com", context.Background(), records)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0] != "mass_edit_zone: serial mismatch" {
+		t.Errorf("apiErr.Errors = %v", apiErr.Errors)
+	}
+}
+
+// TestStreamPageRetriesTransientServerError guards against StreamRecords regressing to
+// a bare, unretried request: a single transient 500 on one page of a large zone should
+// be retried, the same as dial retries one for any other UAPI call, rather than
+// aborting the whole listing.
+func TestStreamPageRetriesTransientServerError(t *testing.T) {
+	var attempts int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/parse_zone", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"result":{"status":1,"data":[{"line_index":1,"record_type":"A","ttl":300,"dname_b64":%q,"data_b64":[%q]}],"errors":[],"messages":[]}}`,
+			encodeB64("www.example.com"), encodeB64("192.0.2.1"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	records := make(chan libdns.Record, 1)
+	count, err := p.streamPage(context.Background(), "example.com", 0, records)
+	if err != nil {
+		t.Fatalf("streamPage: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Fatalf("attempts = %d, want 2 (one failure, one retry)", got)
+	}
+
+	r := <-records
+	if r.Type != "A" || r.Value != "192.0.2.1" {
+		t.Errorf("got %+v, want A 192.0.2.1", r)
+	}
+}
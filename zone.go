@@ -0,0 +1,91 @@
+package cpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// LookupZone resolves the cPanel zone that serves the given domain or FQDN. Callers
+// that already know the exact zone name can still pass it here: it is simply the
+// first candidate tried. The result is cached on the Provider so repeated lookups for
+// the same name are free.
+func (p *Provider) LookupZone(ctx context.Context, fqdn string) (string, error) {
+	fqdn = strings.TrimSuffix(fqdn, ".")
+
+	p.zoneCacheMu.Lock()
+	if zone, ok := p.zoneCache[fqdn]; ok {
+		p.zoneCacheMu.Unlock()
+		return zone, nil
+	}
+	p.zoneCacheMu.Unlock()
+
+	zone, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return "", err
+	}
+
+	p.zoneCacheMu.Lock()
+	if p.zoneCache == nil {
+		p.zoneCache = make(map[string]string)
+	}
+	p.zoneCache[fqdn] = zone
+	p.zoneCacheMu.Unlock()
+
+	return zone, nil
+}
+
+// findZone walks progressively shorter parent labels of fqdn (e.g.
+// "_acme-challenge.foo.bar.example.com", "foo.bar.example.com", "bar.example.com", ...)
+// asking cPanel whether it has local authority over each, stopping at the first label
+// set that the account actually serves. This mirrors the zone-detection pattern used
+// by other libdns providers (e.g. easydns) for callers that only know an FQDN.
+func (p *Provider) findZone(ctx context.Context, fqdn string) (string, error) {
+	labels := strings.Split(fqdn, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+
+		ok, err := p.hasLocalAuthority(ctx, candidate)
+		if err != nil {
+			if isZoneNotServedErr(err) {
+				continue
+			}
+			return "", err
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("cpanel: no zone found serving %q", fqdn)
+}
+
+// isZoneNotServedErr reports whether err from hasLocalAuthority's parse_zone fallback
+// just means the probed candidate isn't a zone this account serves - a *APIError, since
+// cPanel successfully answered and said so - as opposed to a genuine transport or
+// authentication failure (a non-2xx httpError, a network error, a malformed response),
+// which findZone must propagate instead of silently trying the next candidate.
+func isZoneNotServedErr(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr)
+}
+
+// hasLocalAuthority reports whether the cPanel account serves the given domain as a
+// zone, via DNS::has_local_authority. Hosts that predate that endpoint are probed by
+// attempting parse_zone instead, since a missing zone fails there too.
+func (p *Provider) hasLocalAuthority(ctx context.Context, domain string) (bool, error) {
+	raw, err := p.dial(ctx, "DNS", "has_local_authority", url.Values{"domain": {domain}})
+	if err == nil {
+		var hasAuthority bool
+		if jsonErr := json.Unmarshal(raw, &hasAuthority); jsonErr == nil {
+			return hasAuthority, nil
+		}
+	}
+
+	if _, err := p.dial(ctx, "DNS", "parse_zone", url.Values{"zone": {domain}}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package cpanel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestFindZoneSwallowsNotServedSkipsToParentLabel verifies findZone continues probing
+// shorter labels when a candidate genuinely isn't served (an *APIError from cPanel),
+// and stops at the first label the account does serve.
+func TestFindZoneSwallowsNotServedSkipsToParentLabel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"status":0,"data":null,"errors":["has_local_authority not available"],"messages":[]}}`)
+	})
+	mux.HandleFunc("/execute/DNS/parse_zone", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("zone") == "example.com" {
+			fmt.Fprint(w, `{"result":{"status":1,"data":[],"errors":[],"messages":[]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"result":{"status":0,"data":null,"errors":["Zone does not exist"],"messages":[]}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	zone, err := p.findZone(context.Background(), "www.foo.example.com")
+	if err != nil {
+		t.Fatalf("findZone: %v", err)
+	}
+	if zone != "example.com" {
+		t.Errorf("zone = %q, want %q", zone, "example.com")
+	}
+}
+
+// TestFindZoneStopsAtGenuineTransportError verifies findZone propagates a genuine
+// transport/auth failure immediately instead of masking it as "no zone found", and
+// doesn't keep probing shorter labels once that happens.
+func TestFindZoneStopsAtGenuineTransportError(t *testing.T) {
+	var parseZoneCalls int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/has_local_authority", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"status":0,"data":null,"errors":["has_local_authority not available"],"messages":[]}}`)
+	})
+	mux.HandleFunc("/execute/DNS/parse_zone", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&parseZoneCalls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, "Login Attempt Failed")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	_, err := p.findZone(context.Background(), "www.foo.example.com")
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if isZoneNotServedErr(err) {
+		t.Errorf("findZone returned a zone-not-served error for a transport failure: %v", err)
+	}
+	if got := atomic.LoadInt32(&parseZoneCalls); got != 1 {
+		t.Errorf("parse_zone called %d times, want 1 (should stop after the genuine error)", got)
+	}
+}
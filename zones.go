@@ -0,0 +1,111 @@
+package cpanel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/libdns/libdns"
+)
+
+// zonesPageSize bounds how many zones ListZones requests per UAPI call, so very large
+// resellers don't receive (and have to buffer) every zone in a single response.
+const zonesPageSize = 200
+
+// listZoneEntry models one entry of DNS::list_zones.
+type listZoneEntry struct {
+	Domain string `json:"domain"`
+	Serial string `json:"serial"`
+	Type   string `json:"zone_type"`
+}
+
+// listDomainEntry models one entry of DomainInfo::list_domains, used as a fallback on
+// hosts where DNS::list_zones isn't available.
+type listDomainEntry struct {
+	Domain string `json:"domain"`
+	Type   string `json:"domain_type"`
+}
+
+// ListZones enumerates the zones owned by the authenticated cPanel user via UAPI
+// DNS::list_zones, falling back to DomainInfo::list_domains where the former isn't
+// available. It pages through results internally (zonesPageSize at a time) so very
+// large resellers don't have to buffer an unbounded response in a single call.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	zones, err := p.listZonesDNS(ctx)
+	if err == nil {
+		return zones, nil
+	}
+	return p.listZonesDomainInfo(ctx)
+}
+
+func (p *Provider) listZonesDNS(ctx context.Context) ([]libdns.Zone, error) {
+	var zones []libdns.Zone
+	var lastFirstDomain string
+	for offset := 0; ; offset += zonesPageSize {
+		params := url.Values{
+			"api.chunk.start": {strconv.Itoa(offset)},
+			"api.chunk.size":  {strconv.Itoa(zonesPageSize)},
+		}
+		raw, err := p.dial(ctx, "DNS", "list_zones", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []listZoneEntry
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return zones, nil
+		}
+		if offset > 0 && entries[0].Domain == lastFirstDomain {
+			return nil, fmt.Errorf("cpanel: DNS::list_zones returned the same page twice at offset %d; host may not honor api.chunk.start", offset)
+		}
+		lastFirstDomain = entries[0].Domain
+
+		for _, e := range entries {
+			zones = append(zones, libdns.Zone{Name: e.Domain, Serial: e.Serial, Type: e.Type})
+		}
+		if len(entries) < zonesPageSize {
+			return zones, nil
+		}
+	}
+}
+
+func (p *Provider) listZonesDomainInfo(ctx context.Context) ([]libdns.Zone, error) {
+	var zones []libdns.Zone
+	var lastFirstDomain string
+	for offset := 0; ; offset += zonesPageSize {
+		params := url.Values{
+			"api.chunk.start": {strconv.Itoa(offset)},
+			"api.chunk.size":  {strconv.Itoa(zonesPageSize)},
+		}
+		raw, err := p.dial(ctx, "DomainInfo", "list_domains", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []listDomainEntry
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return zones, nil
+		}
+		if offset > 0 && entries[0].Domain == lastFirstDomain {
+			return nil, fmt.Errorf("cpanel: DomainInfo::list_domains returned the same page twice at offset %d; host may not honor api.chunk.start", offset)
+		}
+		lastFirstDomain = entries[0].Domain
+
+		for _, e := range entries {
+			zones = append(zones, libdns.Zone{Name: e.Domain, Type: e.Type})
+		}
+		if len(entries) < zonesPageSize {
+			return zones, nil
+		}
+	}
+}
+
+var _ libdns.ZoneLister = (*Provider)(nil)
@@ -0,0 +1,109 @@
+package cpanel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// zonePage renders n list_zones-shaped entries starting at "zoneN" for the given
+// offset, the same shape ListZones' page-termination logic has to reason about.
+func zonePage(offset, n int) string {
+	entries := ""
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			entries += ","
+		}
+		entries += fmt.Sprintf(`{"domain":"zone%d.example","serial":"1","zone_type":"master"}`, offset+i)
+	}
+	return fmt.Sprintf(`{"result":{"status":1,"data":[%s],"errors":[],"messages":[]}}`, entries)
+}
+
+func TestListZonesDNSPagesAcrossMultiplePages(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/list_zones", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("api.chunk.start"))
+		switch offset {
+		case 0:
+			fmt.Fprint(w, zonePage(0, zonesPageSize))
+		case zonesPageSize:
+			fmt.Fprint(w, zonePage(zonesPageSize, 3))
+		default:
+			fmt.Fprint(w, zonePage(offset, 0))
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	zones, err := p.listZonesDNS(context.Background())
+	if err != nil {
+		t.Fatalf("listZonesDNS: %v", err)
+	}
+	if want := zonesPageSize + 3; len(zones) != want {
+		t.Fatalf("len(zones) = %d, want %d", len(zones), want)
+	}
+	if zones[0].Name != "zone0.example" || zones[len(zones)-1].Name != fmt.Sprintf("zone%d.example", zonesPageSize+2) {
+		t.Errorf("unexpected zone ordering: first=%q last=%q", zones[0].Name, zones[len(zones)-1].Name)
+	}
+}
+
+// TestListZonesDNSDetectsNonAdvancingHost guards against an infinite loop on a host
+// that ignores api.chunk.start and keeps returning the same full page: without this
+// check, len(entries) would never drop below zonesPageSize and ListZones would hang.
+func TestListZonesDNSDetectsNonAdvancingHost(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DNS/list_zones", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, zonePage(0, zonesPageSize))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	if _, err := p.listZonesDNS(context.Background()); err == nil {
+		t.Fatal("expected an error for a host that never advances past the first page, got nil")
+	}
+}
+
+func TestListZonesDomainInfoPagesAcrossMultiplePages(t *testing.T) {
+	domainPage := func(offset, n int) string {
+		entries := ""
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				entries += ","
+			}
+			entries += fmt.Sprintf(`{"domain":"zone%d.example","domain_type":"addon"}`, offset+i)
+		}
+		return fmt.Sprintf(`{"result":{"status":1,"data":[%s],"errors":[],"messages":[]}}`, entries)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute/DomainInfo/list_domains", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("api.chunk.start"))
+		switch offset {
+		case 0:
+			fmt.Fprint(w, domainPage(0, zonesPageSize))
+		case zonesPageSize:
+			fmt.Fprint(w, domainPage(zonesPageSize, 2))
+		default:
+			fmt.Fprint(w, domainPage(offset, 0))
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := &Provider{Host: server.URL, Username: "user", APIToken: "token"}
+
+	zones, err := p.listZonesDomainInfo(context.Background())
+	if err != nil {
+		t.Fatalf("listZonesDomainInfo: %v", err)
+	}
+	if want := zonesPageSize + 2; len(zones) != want {
+		t.Fatalf("len(zones) = %d, want %d", len(zones), want)
+	}
+}